@@ -0,0 +1,210 @@
+package repeatgenome
+
+import (
+    "math"
+)
+
+// FilterMinsByCaseControl prunes repeatGenome's minimizer map down to the
+// minimizers that differ significantly between two labelled read sets (e.g.
+// tumor vs. normal, or two strains). For every minimizer bucket it builds a
+// 2x2 contingency table of (reads hitting it in caseReads, reads not
+// hitting it in caseReads, ...same for ctrlReads), runs a Yates-corrected
+// chi-square test, and keeps only minimizers with p <= maxP.
+func (repeatGenome *RepeatGenome) FilterMinsByCaseControl(caseReads, ctrlReads [][]byte, maxP float64) (map[uint64]Kmers, error) {
+    k := repeatGenome.K
+    m := repeatGenome.M
+
+    caseHits := countMinHits(caseReads, k, m)
+    ctrlHits := countMinHits(ctrlReads, k, m)
+
+    numCases := float64(len(caseReads))
+    numCtrls := float64(len(ctrlReads))
+
+    filtered := make(map[uint64]Kmers)
+    for minInt, kmers := range repeatGenome.MinMap {
+        a := float64(caseHits[minInt])
+        b := numCases - a
+        c := float64(ctrlHits[minInt])
+        d := numCtrls - c
+
+        if yatesChiSquareP(a, b, c, d) <= maxP {
+            filtered[minInt] = kmers
+        }
+    }
+    return filtered, nil
+}
+
+// countMinHits returns, for every distinct minimizer that MinMap could have
+// selected, the number of reads in which it actually wins at least one
+// k-window -- i.e. the same population MinMap's keys are drawn from, not
+// every raw m-mer substring. For each k-long window of each read it picks
+// the window's minimizer the same way minimize.go's getMinimizer does
+// (lexicographically-least m-mer, ties broken by earliest position), which
+// for the 2-bit encoding encodeSeq produces is equivalent to least integer
+// value.
+func countMinHits(reads [][]byte, k, m uint8) map[uint64]uint64 {
+    hits := make(map[uint64]uint64)
+    seenInRead := make(map[uint64]bool)
+    for _, read := range reads {
+        for minInt := range seenInRead {
+            delete(seenInRead, minInt)
+        }
+        for i := 0; i+int(k) <= len(read); i++ {
+            minInt, ok := windowMinimizer(read[i:i+int(k)], m)
+            if !ok {
+                continue
+            }
+            seenInRead[minInt] = true
+        }
+        for minInt := range seenInRead {
+            hits[minInt]++
+        }
+    }
+    return hits
+}
+
+// windowMinimizer returns the minimizer of a single k-long window: the
+// lexicographically-least of its m-long sub-sequences, ties broken by
+// earliest position (matching minimize.go's getMinimizer), encoded the same
+// way MinMap's keys are. ok is false if the window contains no valid m-mer
+// (e.g. every one of them has an ambiguity code).
+func windowMinimizer(window []byte, m uint8) (minInt uint64, ok bool) {
+    for i := 0; i+int(m) <= len(window); i++ {
+        seqInt, seqOk := encodeSeq(window[i : i+int(m)])
+        if !seqOk {
+            continue
+        }
+        if !ok || seqInt < minInt {
+            minInt = seqInt
+            ok = true
+        }
+    }
+    return minInt, ok
+}
+
+// encodeSeq packs an ACGT (case-insensitive) byte slice into the same
+// 2-bit-per-base uint64 encoding fillSeq() unpacks, returning ok == false if
+// the slice contains an ambiguity code (e.g. 'N') or is too long to fit.
+func encodeSeq(seq []byte) (seqInt uint64, ok bool) {
+    if len(seq) > 32 {
+        return 0, false
+    }
+    for _, base := range seq {
+        var bits uint64
+        switch base {
+        case 'a', 'A':
+            bits = 0
+        case 'c', 'C':
+            bits = 1
+        case 'g', 'G':
+            bits = 2
+        case 't', 'T':
+            bits = 3
+        default:
+            return 0, false
+        }
+        seqInt = (seqInt << 2) | bits
+    }
+    return seqInt, true
+}
+
+// yatesChiSquareP runs a Yates-corrected chi-square test of independence on
+// a 2x2 contingency table with the given observed counts, returning the
+// p-value (1 dof). Cells with expected count < 5 make the test unreliable,
+// so those tables are treated as p == 1 (no evidence of a difference).
+func yatesChiSquareP(a, b, c, d float64) float64 {
+    row1, row2 := a+b, c+d
+    col1, col2 := a+c, b+d
+    n := row1 + row2
+    if n == 0 {
+        return 1
+    }
+
+    e11 := row1 * col1 / n
+    e12 := row1 * col2 / n
+    e21 := row2 * col1 / n
+    e22 := row2 * col2 / n
+    if e11 < 5 || e12 < 5 || e21 < 5 || e22 < 5 {
+        return 1
+    }
+
+    chiSq := yatesTerm(a, e11) + yatesTerm(b, e12) + yatesTerm(c, e21) + yatesTerm(d, e22)
+    return gammaincQ(0.5, chiSq/2)
+}
+
+func yatesTerm(o, e float64) float64 {
+    diff := math.Abs(o-e) - 0.5
+    if diff < 0 {
+        diff = 0
+    }
+    return diff * diff / e
+}
+
+// The constants and gammaincQ/gammaSeries/gammaCF below implement the
+// regularized upper incomplete gamma function Q(a, x) = Gamma(a,x)/Gamma(a)
+// via the classic series/continued-fraction split (series converges fast
+// for x < a+1, the continued fraction for x >= a+1), so that chi-square
+// p-values can be computed without an external stats dependency.
+const (
+    gammaIncMaxIter = 100
+    gammaIncEps     = 3e-12
+    gammaIncFPMin   = 1e-300
+)
+
+func gammaincQ(a, x float64) float64 {
+    if x < 0 || a <= 0 {
+        panic("gammaincQ: invalid arguments")
+    }
+    if x == 0 {
+        return 1
+    }
+    if x < a+1 {
+        return 1 - gammaSeries(a, x)
+    }
+    return gammaCF(a, x)
+}
+
+func gammaSeries(a, x float64) float64 {
+    lgamma, _ := math.Lgamma(a)
+
+    ap := a
+    sum := 1 / a
+    del := sum
+    for n := 0; n < gammaIncMaxIter; n++ {
+        ap++
+        del *= x / ap
+        sum += del
+        if math.Abs(del) < math.Abs(sum)*gammaIncEps {
+            break
+        }
+    }
+    return sum * math.Exp(-x+a*math.Log(x)-lgamma)
+}
+
+func gammaCF(a, x float64) float64 {
+    lgamma, _ := math.Lgamma(a)
+
+    b := x + 1 - a
+    c := 1 / gammaIncFPMin
+    d := 1 / b
+    h := d
+    for i := 1; i <= gammaIncMaxIter; i++ {
+        an := -float64(i) * (float64(i) - a)
+        b += 2
+        d = an*d + b
+        if math.Abs(d) < gammaIncFPMin {
+            d = gammaIncFPMin
+        }
+        c = b + an/c
+        if math.Abs(c) < gammaIncFPMin {
+            c = gammaIncFPMin
+        }
+        d = 1 / d
+        del := d * c
+        h *= del
+        if math.Abs(del-1) < gammaIncEps {
+            break
+        }
+    }
+    return math.Exp(-x+a*math.Log(x)-lgamma) * h
+}
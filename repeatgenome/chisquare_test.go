@@ -0,0 +1,89 @@
+package repeatgenome
+
+import "testing"
+
+func TestYatesChiSquareP(t *testing.T) {
+    const tol = 1e-9
+    cases := []struct {
+        a, b, c, d float64
+        want       float64
+    }{
+        {10, 10, 20, 10, 0.376759117811582},
+        {50, 50, 50, 50, 1.0},
+        {100, 10, 10, 100, 0.0}, // well below tol; checked separately below
+    }
+
+    for _, c := range cases {
+        got := yatesChiSquareP(c.a, c.b, c.c, c.d)
+        if c.a == 100 {
+            if got >= 1e-20 {
+                t.Errorf("yatesChiSquareP(%v,%v,%v,%v) = %v, want a vanishingly small p-value", c.a, c.b, c.c, c.d, got)
+            }
+            continue
+        }
+        if diff := got - c.want; diff < -tol || diff > tol {
+            t.Errorf("yatesChiSquareP(%v,%v,%v,%v) = %v, want %v", c.a, c.b, c.c, c.d, got, c.want)
+        }
+    }
+}
+
+func TestYatesChiSquarePSmallExpectedCounts(t *testing.T) {
+    // every expected cell is well under 5, so the test should bail out to
+    // p == 1 rather than trust the chi-square approximation.
+    if got := yatesChiSquareP(1, 1, 1, 1); got != 1 {
+        t.Errorf("yatesChiSquareP(1,1,1,1) = %v, want 1 (expected counts < 5)", got)
+    }
+}
+
+func TestWindowMinimizer(t *testing.T) {
+    // windows are "ACG"(6), "CGT"(27), "GTA"(44), "TAC"(49), "ACG"(6), "CGT"(27);
+    // the least-valued (and first-occurring) 3-mer is "ACG" at offset 0.
+    got, ok := windowMinimizer([]byte("ACGTACGT"), 3)
+    if !ok {
+        t.Fatal("windowMinimizer returned ok == false for an all-ACGT window")
+    }
+    want, wantOk := encodeSeq([]byte("ACG"))
+    if !wantOk || got != want {
+        t.Fatalf("windowMinimizer(\"ACGTACGT\", 3) = %d, want %d (\"ACG\")", got, want)
+    }
+}
+
+func TestWindowMinimizerAllAmbiguous(t *testing.T) {
+    if _, ok := windowMinimizer([]byte("NNNNN"), 3); ok {
+        t.Fatal("windowMinimizer returned ok == true for a window with no valid m-mer")
+    }
+}
+
+func TestCountMinHits(t *testing.T) {
+    // k=4, m=3: the read's only k-window is "ACGT", whose minimizer is "ACG".
+    reads := [][]byte{[]byte("ACGT"), []byte("ACGT"), []byte("TTTT")}
+    hits := countMinHits(reads, 4, 3)
+
+    acg, _ := encodeSeq([]byte("ACG"))
+    if hits[acg] != 2 {
+        t.Errorf("hits[\"ACG\"] = %d, want 2 (present in 2 of 3 reads)", hits[acg])
+    }
+
+    ttt, _ := encodeSeq([]byte("TTT"))
+    if hits[ttt] != 1 {
+        t.Errorf("hits[\"TTT\"] = %d, want 1 (present in 1 of 3 reads)", hits[ttt])
+    }
+}
+
+func TestGammaincQKnownValues(t *testing.T) {
+    const tol = 1e-9
+    cases := []struct {
+        a, x, want float64
+    }{
+        {0.5, 0, 1},
+        {1, 0, 1},
+        {1, 1, 0.36787944117144233}, // Q(1, x) = e^-x
+        {1, 5, 0.006737946999085467},
+    }
+    for _, c := range cases {
+        got := gammaincQ(c.a, c.x)
+        if diff := got - c.want; diff < -tol || diff > tol {
+            t.Errorf("gammaincQ(%v, %v) = %v, want %v", c.a, c.x, got, c.want)
+        }
+    }
+}
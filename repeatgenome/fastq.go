@@ -0,0 +1,251 @@
+package repeatgenome
+
+import (
+    "bufio"
+    "fmt"
+    "io"
+
+    "github.com/klauspost/pgzip"
+)
+
+// Read is a single sequenced read, as produced by FASTQReader: a name, its
+// base sequence, and its per-base Phred+33 quality string.
+type Read struct {
+    Name string
+    Seq  []byte
+    Qual []byte
+}
+
+// PairResponse pairs the two mates of a paired-end read with the
+// classification assigned to the pair as a whole: the LCA of each mate's
+// individual classification.
+type PairResponse struct {
+    Read1, Read2 Read
+    ClassNode    *ClassNode
+}
+
+// FASTQReader streams Read records from an io.Reader, transparently
+// decompressing gzip input. Compression is detected from the stream's
+// magic bytes rather than a filename suffix, so it works regardless of how
+// the caller obtained the io.Reader.
+type FASTQReader struct {
+    r      *bufio.Reader
+    closer io.Closer
+}
+
+// NewFASTQReader wraps r for reading FASTQ records.
+func NewFASTQReader(r io.Reader) (*FASTQReader, error) {
+    br := bufio.NewReader(r)
+    magic, err := br.Peek(2)
+    if err != nil && err != io.EOF {
+        return nil, err
+    }
+    if len(magic) == 2 && magic[0] == 0x1f && magic[1] == 0x8b {
+        gz, err := pgzip.NewReader(br)
+        if err != nil {
+            return nil, err
+        }
+        return &FASTQReader{r: bufio.NewReader(gz), closer: gz}, nil
+    }
+    return &FASTQReader{r: br}, nil
+}
+
+// Next reads the next FASTQ record (the standard 4 lines: @name, seq, +,
+// qual), returning io.EOF once the input is exhausted.
+func (fr *FASTQReader) Next() (Read, error) {
+    nameLine, err := fr.r.ReadString('\n')
+    if err != nil {
+        if err == io.EOF && len(nameLine) == 0 {
+            return Read{}, io.EOF
+        }
+        return Read{}, err
+    }
+    nameLine = trimNewline(nameLine)
+    if len(nameLine) == 0 || nameLine[0] != '@' {
+        return Read{}, fmt.Errorf("repeatgenome: malformed FASTQ record: expected '@name', got %q", nameLine)
+    }
+
+    seqLine, err := fr.r.ReadString('\n')
+    if err != nil {
+        return Read{}, err
+    }
+    plusLine, err := fr.r.ReadString('\n')
+    if err != nil {
+        return Read{}, err
+    }
+    plusLine = trimNewline(plusLine)
+    if len(plusLine) == 0 || plusLine[0] != '+' {
+        return Read{}, fmt.Errorf("repeatgenome: malformed FASTQ record: expected '+', got %q", plusLine)
+    }
+    qualLine, err := fr.r.ReadString('\n')
+    if err != nil && err != io.EOF {
+        return Read{}, err
+    }
+
+    return Read{
+        Name: nameLine[1:],
+        Seq:  []byte(trimNewline(seqLine)),
+        Qual: []byte(trimNewline(qualLine)),
+    }, nil
+}
+
+// Close releases any gzip reader NewFASTQReader opened.
+func (fr *FASTQReader) Close() error {
+    if fr.closer != nil {
+        return fr.closer.Close()
+    }
+    return nil
+}
+
+func trimNewline(s string) string {
+    for len(s) > 0 && (s[len(s)-1] == '\n' || s[len(s)-1] == '\r') {
+        s = s[:len(s)-1]
+    }
+    return s
+}
+
+// maskLowQual rewrites bases in seq whose Phred+33 quality score is below
+// minQual to 'N', so the minimizer path skips them the same way it already
+// skips ambiguity codes. minQual <= 0 disables masking.
+func maskLowQual(seq, qual []byte, minQual int) {
+    if minQual <= 0 {
+        return
+    }
+    for i := 0; i < len(seq) && i < len(qual); i++ {
+        if int(qual[i])-33 < minQual {
+            seq[i] = 'N'
+        }
+    }
+}
+
+// fastqBatchSize bounds how many reads ClassifyFASTQ buffers before handing
+// them to GetReadClassChan as a single batch. Classifying one read at a time
+// pays GetReadClassChan's per-call overhead on every read; batching amortizes
+// it the same way the .proc-file path already does by classifying a whole
+// file's reads in one call.
+const fastqBatchSize = 4096
+
+// ClassifyFASTQ streams and classifies records from r in batches of up to
+// fastqBatchSize, quality-masking low-scoring bases first, bypassing the
+// .proc-file scan entirely.
+func (repeatGenome *RepeatGenome) ClassifyFASTQ(r io.Reader, minQual int) (<-chan ReadResponse, error) {
+    fr, err := NewFASTQReader(r)
+    if err != nil {
+        return nil, err
+    }
+
+    out := make(chan ReadResponse)
+    go func() {
+        defer close(out)
+        defer fr.Close()
+
+        var index uint64
+        batch := make([][]byte, 0, fastqBatchSize)
+        flush := func() {
+            if len(batch) == 0 {
+                return
+            }
+            for resp := range repeatGenome.GetReadClassChan(batch) {
+                resp.Index = index
+                out <- resp
+                index++
+            }
+            batch = batch[:0]
+        }
+
+        for {
+            read, err := fr.Next()
+            if err != nil {
+                flush()
+                return
+            }
+            maskLowQual(read.Seq, read.Qual, minQual)
+            batch = append(batch, read.Seq)
+            if len(batch) >= fastqBatchSize {
+                flush()
+            }
+        }
+    }()
+    return out, nil
+}
+
+// fastqPairBatchSize is ClassifyFASTQPair's analog of fastqBatchSize: each
+// mate's reads are classified fastqPairBatchSize at a time (rather than
+// fastqBatchSize, since a pair's two GetReadClassChan calls are in flight
+// per buffered batch instead of one).
+const fastqPairBatchSize = 2048
+
+// ClassifyFASTQPair interleaves r1 and r2, classifying each mate in batches
+// and emitting one PairResponse per pair whose ClassNode is the LCA of the
+// two mates' classifications.
+func (repeatGenome *RepeatGenome) ClassifyFASTQPair(r1, r2 io.Reader, minQual int) (<-chan PairResponse, error) {
+    fr1, err := NewFASTQReader(r1)
+    if err != nil {
+        return nil, err
+    }
+    fr2, err := NewFASTQReader(r2)
+    if err != nil {
+        fr1.Close()
+        return nil, err
+    }
+
+    out := make(chan PairResponse)
+    go func() {
+        defer close(out)
+        defer fr1.Close()
+        defer fr2.Close()
+
+        pairs := make([]Read, 0, fastqPairBatchSize)
+        mates := make([]Read, 0, fastqPairBatchSize)
+        seqs1 := make([][]byte, 0, fastqPairBatchSize)
+        seqs2 := make([][]byte, 0, fastqPairBatchSize)
+
+        flush := func() {
+            if len(pairs) == 0 {
+                return
+            }
+            classes1 := collectReadClasses(repeatGenome.GetReadClassChan(seqs1), len(seqs1))
+            classes2 := collectReadClasses(repeatGenome.GetReadClassChan(seqs2), len(seqs2))
+            for i := range pairs {
+                out <- PairResponse{
+                    Read1:     pairs[i],
+                    Read2:     mates[i],
+                    ClassNode: repeatGenome.ClassTree.LCA(classes1[i], classes2[i]),
+                }
+            }
+            pairs, mates, seqs1, seqs2 = pairs[:0], mates[:0], seqs1[:0], seqs2[:0]
+        }
+
+        for {
+            read1, err1 := fr1.Next()
+            read2, err2 := fr2.Next()
+            if err1 != nil || err2 != nil {
+                flush()
+                return
+            }
+            maskLowQual(read1.Seq, read1.Qual, minQual)
+            maskLowQual(read2.Seq, read2.Qual, minQual)
+
+            pairs = append(pairs, read1)
+            mates = append(mates, read2)
+            seqs1 = append(seqs1, read1.Seq)
+            seqs2 = append(seqs2, read2.Seq)
+            if len(pairs) >= fastqPairBatchSize {
+                flush()
+            }
+        }
+    }()
+    return out, nil
+}
+
+// collectReadClasses drains a GetReadClassChan result into a []*ClassNode of
+// the given length, indexed by each response's position in the batch it was
+// classified from (GetReadClassChan preserves input order, the same
+// assumption ClassifyShards makes when it assigns Index off the channel).
+func collectReadClasses(respChan <-chan ReadResponse, n int) []*ClassNode {
+    classes := make([]*ClassNode, 0, n)
+    for resp := range respChan {
+        classes = append(classes, resp.ClassNode)
+    }
+    return classes
+}
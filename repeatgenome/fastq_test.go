@@ -0,0 +1,95 @@
+package repeatgenome
+
+import (
+    "bytes"
+    "compress/gzip"
+    "io"
+    "testing"
+)
+
+const fastqSample = "@read1\nACGTN\n+\nIIIII\n@read2\nTTTTT\n+\n!!!!!\n"
+
+func TestFASTQReaderNext(t *testing.T) {
+    fr, err := NewFASTQReader(bytes.NewReader([]byte(fastqSample)))
+    if err != nil {
+        t.Fatalf("NewFASTQReader: %v", err)
+    }
+    defer fr.Close()
+
+    read1, err := fr.Next()
+    if err != nil {
+        t.Fatalf("Next (read1): %v", err)
+    }
+    if read1.Name != "read1" || string(read1.Seq) != "ACGTN" || string(read1.Qual) != "IIIII" {
+        t.Fatalf("read1 = %+v, want name=read1 seq=ACGTN qual=IIIII", read1)
+    }
+
+    read2, err := fr.Next()
+    if err != nil {
+        t.Fatalf("Next (read2): %v", err)
+    }
+    if read2.Name != "read2" || string(read2.Seq) != "TTTTT" || string(read2.Qual) != "!!!!!" {
+        t.Fatalf("read2 = %+v, want name=read2 seq=TTTTT qual=!!!!!", read2)
+    }
+
+    if _, err := fr.Next(); err != io.EOF {
+        t.Fatalf("Next (after last record) = %v, want io.EOF", err)
+    }
+}
+
+func TestFASTQReaderNextGzip(t *testing.T) {
+    var buf bytes.Buffer
+    gz := gzip.NewWriter(&buf)
+    if _, err := gz.Write([]byte(fastqSample)); err != nil {
+        t.Fatalf("gzip.Write: %v", err)
+    }
+    if err := gz.Close(); err != nil {
+        t.Fatalf("gzip.Close: %v", err)
+    }
+
+    fr, err := NewFASTQReader(&buf)
+    if err != nil {
+        t.Fatalf("NewFASTQReader: %v", err)
+    }
+    defer fr.Close()
+
+    read1, err := fr.Next()
+    if err != nil {
+        t.Fatalf("Next (read1): %v", err)
+    }
+    if read1.Name != "read1" || string(read1.Seq) != "ACGTN" {
+        t.Fatalf("read1 = %+v, want name=read1 seq=ACGTN", read1)
+    }
+}
+
+func TestFASTQReaderNextMalformed(t *testing.T) {
+    fr, err := NewFASTQReader(bytes.NewReader([]byte("not-a-name-line\nACGT\n+\nIIII\n")))
+    if err != nil {
+        t.Fatalf("NewFASTQReader: %v", err)
+    }
+    defer fr.Close()
+
+    if _, err := fr.Next(); err == nil {
+        t.Fatal("Next() on a record missing the leading '@' = nil error, want non-nil")
+    }
+}
+
+func TestMaskLowQual(t *testing.T) {
+    seq := []byte("ACGTA")
+    qual := []byte("III!!") // Phred+33: 'I' = 40, '!' = 0
+    maskLowQual(seq, qual, 20)
+
+    if got, want := string(seq), "ACGNN"; got != want {
+        t.Fatalf("maskLowQual masked seq = %q, want %q", got, want)
+    }
+}
+
+func TestMaskLowQualDisabled(t *testing.T) {
+    seq := []byte("ACGTA")
+    qual := []byte("!!!!!")
+    maskLowQual(seq, qual, 0)
+
+    if got, want := string(seq), "ACGTA"; got != want {
+        t.Fatalf("maskLowQual with minQual<=0 changed seq to %q, want unchanged %q", got, want)
+    }
+}
@@ -0,0 +1,17 @@
+package repeatgenome
+
+// Flags bundles the run-rg.go command-line flags that affect RepeatGenome
+// generation and output, so they can be threaded through Generate() and its
+// helpers without a long parameter list.
+type Flags struct {
+    Debug       bool
+    CPUProfile  bool
+    MemProfile  bool
+    GenKraken   bool
+    WriteKraken bool
+    WriteJSON   bool
+    // Compress gzips every file repeatgenome writes (.mins, .classtree.json,
+    // and any OutputFormat's output) using a parallel gzip writer, and
+    // appends ".gz" to the resulting filename.
+    Compress bool
+}
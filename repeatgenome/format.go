@@ -0,0 +1,72 @@
+package repeatgenome
+
+import (
+    "fmt"
+    "io"
+)
+
+// OutputFormat abstracts over how read classifications are serialized to
+// disk, so that downstream consumers (Kraken-compatible tooling, SAM
+// re-taggers, JSON consumers, ...) can be added without touching the
+// classification loop in run-rg.go.
+//
+// The formats below also rely on a few fields added to the existing
+// ReadResponse type: Index (a stable, zero-based position in the input
+// stream, for formats that need to name reads that don't otherwise carry a
+// name), KmerHits, and SeqName (which ReadSource a response came from, set
+// by ClassifyShards).
+type OutputFormat interface {
+    // Filename returns the name of the file output should be written to,
+    // given the RepeatGenome being classified against.
+    Filename(rg *RepeatGenome) string
+    // Head writes any header required before the first Print call.
+    Head(w io.Writer, rg *RepeatGenome) error
+    // Print writes a single read's classification.
+    Print(w io.Writer, resp ReadResponse) error
+    // Finish writes any trailer and is called once after the last Print call.
+    Finish(w io.Writer) error
+}
+
+// ChromFinisher is optionally implemented by OutputFormat types that need
+// to do per-file cleanup under -per-chrom-output, where one Head/Print
+// sequence runs per chromosome file instead of once for the whole run. If a
+// format doesn't implement it, its ordinary Finish is used for each
+// per-chrom file instead.
+type ChromFinisher interface {
+    FinishChrom(outdir, seqName string, w io.Writer) error
+}
+
+// outputFormats is the registry of known output formats, keyed by the name
+// passed to the -output-format flag.
+var outputFormats = make(map[string]func() OutputFormat)
+
+// RegisterOutputFormat adds a named OutputFormat constructor to the
+// registry. It's meant to be called from an init() in the file defining
+// each format.
+func RegisterOutputFormat(name string, ctor func() OutputFormat) {
+    outputFormats[name] = ctor
+}
+
+// NewOutputFormat looks up a registered output format by name, returning
+// ok == false if no format is registered under that name.
+func NewOutputFormat(name string) (format OutputFormat, ok bool) {
+    ctor, ok := outputFormats[name]
+    if !ok {
+        return nil, false
+    }
+    return ctor(), true
+}
+
+// OutputFormatNames returns the names of all registered output formats,
+// primarily for use in flag usage strings and error messages.
+func OutputFormatNames() []string {
+    names := make([]string, 0, len(outputFormats))
+    for name := range outputFormats {
+        names = append(names, name)
+    }
+    return names
+}
+
+func unknownOutputFormatError(name string) error {
+    return fmt.Errorf("unknown output format %q (known formats: %v)", name, OutputFormatNames())
+}
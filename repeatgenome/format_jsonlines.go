@@ -0,0 +1,52 @@
+package repeatgenome
+
+import (
+    "encoding/json"
+    "io"
+)
+
+func init() {
+    RegisterOutputFormat("json-lines", func() OutputFormat { return new(JSONLinesFormat) })
+}
+
+// jsonLine is the shape of a single line written by JSONLinesFormat.
+type jsonLine struct {
+    Index    uint64 `json:"index"`
+    Len      int    `json:"len"`
+    Class    string `json:"class,omitempty"`
+    ClassID  uint16 `json:"class_id,omitempty"`
+    KmerHits int    `json:"kmer_hits"`
+}
+
+// JSONLinesFormat streams one JSON object per read to the output, making
+// classifications easy to consume from languages without a Kraken parser
+// on hand.
+type JSONLinesFormat struct {
+    enc *json.Encoder
+}
+
+func (*JSONLinesFormat) Filename(rg *RepeatGenome) string {
+    return rg.Name + ".classified.jsonl"
+}
+
+func (f *JSONLinesFormat) Head(w io.Writer, rg *RepeatGenome) error {
+    f.enc = json.NewEncoder(w)
+    return nil
+}
+
+func (f *JSONLinesFormat) Print(w io.Writer, resp ReadResponse) error {
+    line := jsonLine{
+        Index:    resp.Index,
+        Len:      len(resp.Read),
+        KmerHits: resp.KmerHits,
+    }
+    if resp.ClassNode != nil {
+        line.Class = resp.ClassNode.Name
+        line.ClassID = resp.ClassNode.ID
+    }
+    return f.enc.Encode(line)
+}
+
+func (*JSONLinesFormat) Finish(w io.Writer) error {
+    return nil
+}
@@ -0,0 +1,43 @@
+package repeatgenome
+
+import (
+    "fmt"
+    "io"
+)
+
+func init() {
+    RegisterOutputFormat("kraken", func() OutputFormat { return new(KrakenFormat) })
+}
+
+// KrakenFormat writes tab-delimited classification lines matching Kraken's
+// classified-output convention:
+//
+//     C/U  readname  taxID  len  kmerHits
+//
+// "C" marks a classified read and "U" an unclassified one; taxID is the
+// assigned ClassNode's ID, or 0 when unclassified.
+type KrakenFormat struct{}
+
+func (*KrakenFormat) Filename(rg *RepeatGenome) string {
+    return rg.Name + ".kraken"
+}
+
+func (*KrakenFormat) Head(w io.Writer, rg *RepeatGenome) error {
+    return nil
+}
+
+func (*KrakenFormat) Print(w io.Writer, resp ReadResponse) error {
+    status := "U"
+    var taxID uint16
+    if resp.ClassNode != nil {
+        status = "C"
+        taxID = resp.ClassNode.ID
+    }
+    readName := fmt.Sprintf("read_%d", resp.Index)
+    _, err := fmt.Fprintf(w, "%s\t%s\t%d\t%d\t%d\n", status, readName, taxID, len(resp.Read), resp.KmerHits)
+    return err
+}
+
+func (*KrakenFormat) Finish(w io.Writer) error {
+    return nil
+}
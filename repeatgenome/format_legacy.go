@@ -0,0 +1,61 @@
+package repeatgenome
+
+import "io"
+
+func init() {
+    RegisterOutputFormat("mins", func() OutputFormat { return new(MinsFormat) })
+    RegisterOutputFormat("classtree", func() OutputFormat { return new(ClassTreeFormat) })
+}
+
+// MinsFormat adapts the pre-existing RepeatGenome.WriteMins writer to the
+// OutputFormat interface. It ignores the per-read Print calls entirely: the
+// minimizer map isn't derived from individual read classifications, so the
+// whole file is written in one shot once classification finishes -- through
+// the writer the caller already opened via Filename()+CreateOutputFile,
+// never by reopening the path itself.
+type MinsFormat struct {
+    rg *RepeatGenome
+}
+
+func (*MinsFormat) Filename(rg *RepeatGenome) string {
+    return rg.Name + ".mins"
+}
+
+func (f *MinsFormat) Head(w io.Writer, rg *RepeatGenome) error {
+    f.rg = rg
+    return nil
+}
+
+func (*MinsFormat) Print(w io.Writer, resp ReadResponse) error {
+    return nil
+}
+
+func (f *MinsFormat) Finish(w io.Writer) error {
+    return f.rg.writeMinsTo(w, f.rg.MinMap)
+}
+
+// ClassTreeFormat adapts the pre-existing RepeatGenome.WriteClassJSON writer
+// to the OutputFormat interface, for the same reason MinsFormat does: the
+// class tree is summarized over all of rg.Kmers, not built read-by-read,
+// and it writes through the caller's already-open writer rather than
+// reopening the path.
+type ClassTreeFormat struct {
+    rg *RepeatGenome
+}
+
+func (*ClassTreeFormat) Filename(rg *RepeatGenome) string {
+    return rg.Name + ".classtree.json"
+}
+
+func (f *ClassTreeFormat) Head(w io.Writer, rg *RepeatGenome) error {
+    f.rg = rg
+    return nil
+}
+
+func (*ClassTreeFormat) Print(w io.Writer, resp ReadResponse) error {
+    return nil
+}
+
+func (f *ClassTreeFormat) Finish(w io.Writer) error {
+    return f.rg.writeClassJSONTo(w, true, true)
+}
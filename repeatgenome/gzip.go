@@ -0,0 +1,101 @@
+package repeatgenome
+
+import (
+    "bufio"
+    "io"
+    "os"
+    "runtime"
+    "strings"
+
+    "github.com/klauspost/pgzip"
+)
+
+// gzipBlockSize is the per-block size pgzip compresses on each worker
+// goroutine. ~1 MiB keeps memory use bounded while still giving the
+// compressor enough work per block to parallelize well.
+const gzipBlockSize = 1 << 20
+
+// CreateOutputFile creates filename for writing, appending ".gz" and
+// wrapping the result in a parallel gzip writer when compress is true. The
+// returned close func flushes and closes every layer (buffered writer,
+// gzip writer, file) in the right order, and must be called instead of
+// closing the file directly.
+func CreateOutputFile(filename string, compress bool) (w *bufio.Writer, closeFunc func() error, err error) {
+    if compress {
+        filename += ".gz"
+    }
+    f, err := os.Create(filename)
+    if err != nil {
+        return nil, nil, err
+    }
+
+    if !compress {
+        bw := bufio.NewWriter(f)
+        return bw, func() error {
+            if ferr := bw.Flush(); ferr != nil {
+                f.Close()
+                return ferr
+            }
+            return f.Close()
+        }, nil
+    }
+
+    gz, err := pgzip.NewWriterLevel(f, pgzip.DefaultCompression)
+    if err != nil {
+        f.Close()
+        return nil, nil, err
+    }
+    if err = gz.SetConcurrency(gzipBlockSize, runtime.GOMAXPROCS(0)); err != nil {
+        gz.Close()
+        f.Close()
+        return nil, nil, err
+    }
+
+    bw := bufio.NewWriter(gz)
+    return bw, func() error {
+        if ferr := bw.Flush(); ferr != nil {
+            gz.Close()
+            f.Close()
+            return ferr
+        }
+        if ferr := gz.Close(); ferr != nil {
+            f.Close()
+            return ferr
+        }
+        return f.Close()
+    }, nil
+}
+
+// OpenInputFile opens filename for reading, transparently wrapping it in a
+// pgzip reader if the name ends in ".gz". Readers that accept compressed
+// input (ParseReadSAMs, and any future .mins loader) should read through
+// this instead of os.Open.
+func OpenInputFile(filename string) (io.ReadCloser, error) {
+    f, err := os.Open(filename)
+    if err != nil {
+        return nil, err
+    }
+    if !strings.HasSuffix(filename, ".gz") {
+        return f, nil
+    }
+    gz, err := pgzip.NewReader(f)
+    if err != nil {
+        f.Close()
+        return nil, err
+    }
+    return &gzipFile{Reader: gz, underlying: f}, nil
+}
+
+// gzipFile closes both the pgzip reader and the underlying file.
+type gzipFile struct {
+    *pgzip.Reader
+    underlying *os.File
+}
+
+func (g *gzipFile) Close() error {
+    if err := g.Reader.Close(); err != nil {
+        g.underlying.Close()
+        return err
+    }
+    return g.underlying.Close()
+}
@@ -0,0 +1,147 @@
+package repeatgenome
+
+import (
+    "bufio"
+    "fmt"
+    "os"
+    "strings"
+    "unsafe"
+)
+
+// npyMagic is the 6-byte magic string that opens every .npy file, followed
+// by the 2-byte format version (1.0) this encoder writes.
+var npyMagic = []byte("\x93NUMPY\x01\x00")
+
+// writeNPYHeader writes the magic, version, header length, and the ASCII
+// dict header itself (padded with spaces to a 64-byte boundary and
+// terminated with '\n', per the .npy v1.0 spec) describing an array of
+// dtype descr and shape.
+func writeNPYHeader(w *bufio.Writer, descr string, shape ...int) error {
+    shapeStrs := make([]string, len(shape))
+    for i, dim := range shape {
+        shapeStrs[i] = fmt.Sprintf("%d", dim)
+    }
+    shapeStr := strings.Join(shapeStrs, ", ")
+    if len(shape) == 1 {
+        shapeStr += ","
+    }
+    dict := fmt.Sprintf("{'descr': '%s', 'fortran_order': False, 'shape': (%s), }", descr, shapeStr)
+
+    // magic(6) + version(2) + header-length field(2) + dict + '\n' must be a
+    // multiple of 64 bytes.
+    const preludeLen = 6 + 2 + 2
+    padding := 64 - (preludeLen+len(dict)+1)%64
+    if padding == 64 {
+        padding = 0
+    }
+    dict += strings.Repeat(" ", padding) + "\n"
+
+    if _, err := w.Write(npyMagic); err != nil {
+        return err
+    }
+    headerLen := uint16(len(dict))
+    if err := w.WriteByte(byte(headerLen)); err != nil {
+        return err
+    }
+    if err := w.WriteByte(byte(headerLen >> 8)); err != nil {
+        return err
+    }
+    _, err := w.WriteString(dict)
+    return err
+}
+
+// WriteKmerMatrixNPY dumps repeatGenome.Kmers as a NumPy array, for
+// consumption by downstream ML pipelines via numpy.load. mode selects the
+// encoding:
+//
+//   - "lca-id": an int32 vector of length len(rg.Kmers), one LCA ClassNode ID
+//     per kmer, plus a companion "<path>.classes.tsv" mapping ID -> Name.
+//   - "one-hot": a uint8 array of shape [numKmers, numClassNodes] with a 1 in
+//     each kmer's LCA column, streamed row-by-row so the whole matrix is
+//     never materialized in memory.
+func (repeatGenome *RepeatGenome) WriteKmerMatrixNPY(path string, mode string) error {
+    switch mode {
+    case "lca-id":
+        return repeatGenome.writeKmerMatrixLCAID(path)
+    case "one-hot":
+        return repeatGenome.writeKmerMatrixOneHot(path)
+    default:
+        return fmt.Errorf("repeatgenome: unknown WriteKmerMatrixNPY mode %q (want \"lca-id\" or \"one-hot\")", mode)
+    }
+}
+
+func (repeatGenome *RepeatGenome) writeKmerMatrixLCAID(path string) error {
+    outfile, err := os.Create(path)
+    if err != nil {
+        return err
+    }
+    defer outfile.Close()
+    writer := bufio.NewWriter(outfile)
+    defer writer.Flush()
+
+    if err := writeNPYHeader(writer, "<i4", len(repeatGenome.Kmers)); err != nil {
+        return err
+    }
+
+    for i := range repeatGenome.Kmers {
+        lcaID := *(*uint16)(unsafe.Pointer(&repeatGenome.Kmers[i][8]))
+        var buf [4]byte
+        *(*int32)(unsafe.Pointer(&buf[0])) = int32(lcaID)
+        if _, err := writer.Write(buf[:]); err != nil {
+            return err
+        }
+    }
+
+    return repeatGenome.writeClassesTSV(path)
+}
+
+func (repeatGenome *RepeatGenome) writeKmerMatrixOneHot(path string) error {
+    outfile, err := os.Create(path)
+    if err != nil {
+        return err
+    }
+    defer outfile.Close()
+    writer := bufio.NewWriter(outfile)
+    defer writer.Flush()
+
+    numClassNodes := len(repeatGenome.ClassTree.NodesByID)
+    if err := writeNPYHeader(writer, "|u1", len(repeatGenome.Kmers), numClassNodes); err != nil {
+        return err
+    }
+
+    row := make([]byte, numClassNodes)
+    for i := range repeatGenome.Kmers {
+        lcaID := *(*uint16)(unsafe.Pointer(&repeatGenome.Kmers[i][8]))
+        if int(lcaID) >= numClassNodes {
+            return fmt.Errorf("repeatgenome: kmer %d has LCA ID %d, out of range for %d class nodes", i, lcaID, numClassNodes)
+        }
+        for j := range row {
+            row[j] = 0
+        }
+        row[lcaID] = 1
+        if _, err := writer.Write(row); err != nil {
+            return err
+        }
+    }
+
+    return repeatGenome.writeClassesTSV(path)
+}
+
+// writeClassesTSV writes the ID -> ClassNode.Name mapping companion file
+// for a kmer matrix written to matrixPath.
+func (repeatGenome *RepeatGenome) writeClassesTSV(matrixPath string) error {
+    outfile, err := os.Create(matrixPath + ".classes.tsv")
+    if err != nil {
+        return err
+    }
+    defer outfile.Close()
+    writer := bufio.NewWriter(outfile)
+    defer writer.Flush()
+
+    for id, classNode := range repeatGenome.ClassTree.NodesByID {
+        if _, err := fmt.Fprintf(writer, "%d\t%s\n", id, classNode.Name); err != nil {
+            return err
+        }
+    }
+    return nil
+}
@@ -0,0 +1,54 @@
+package repeatgenome
+
+import (
+    "bufio"
+    "bytes"
+    "testing"
+)
+
+func TestWriteNPYHeader(t *testing.T) {
+    var buf bytes.Buffer
+    w := bufio.NewWriter(&buf)
+    if err := writeNPYHeader(w, "<i4", 7); err != nil {
+        t.Fatalf("writeNPYHeader: %v", err)
+    }
+    w.Flush()
+
+    got := buf.Bytes()
+    if !bytes.HasPrefix(got, npyMagic) {
+        t.Fatalf("header doesn't start with npyMagic: %x", got[:len(npyMagic)])
+    }
+
+    headerLen := int(got[8]) | int(got[9])<<8
+    total := len(npyMagic) + 2 + headerLen
+    if total%64 != 0 {
+        t.Fatalf("total header length %d is not a multiple of 64", total)
+    }
+    if len(got) != total {
+        t.Fatalf("wrote %d bytes, header says %d", len(got), total)
+    }
+
+    dict := string(got[10:])
+    const want = "{'descr': '<i4', 'fortran_order': False, 'shape': (7,), }"
+    if !bytes.HasPrefix([]byte(dict), []byte(want)) {
+        t.Fatalf("dict = %q, want prefix %q", dict, want)
+    }
+    if dict[len(dict)-1] != '\n' {
+        t.Fatalf("dict doesn't end in a newline: %q", dict)
+    }
+}
+
+func TestWriteNPYHeader2D(t *testing.T) {
+    var buf bytes.Buffer
+    w := bufio.NewWriter(&buf)
+    if err := writeNPYHeader(w, "|u1", 3, 5); err != nil {
+        t.Fatalf("writeNPYHeader: %v", err)
+    }
+    w.Flush()
+
+    dict := string(buf.Bytes()[10:])
+    const want = "{'descr': '|u1', 'fortran_order': False, 'shape': (3, 5), }"
+    if !bytes.HasPrefix([]byte(dict), []byte(want)) {
+        t.Fatalf("dict = %q, want prefix %q", dict, want)
+    }
+}
@@ -0,0 +1,75 @@
+package repeatgenome
+
+import "sync"
+
+// ReadSource is a named collection of reads to classify as a unit -- either
+// one input file (the .proc path) or one chromosome's reads (the SAM path).
+// SeqName is copied onto every ReadResponse produced from it, so per-chrom
+// output (see -per-chrom-output) can demux the shared response channel back
+// out by source.
+//
+// Open is called once per ReadSource, inside the goroutine that classifies
+// it, rather than the caller reading every source's reads up front: with one
+// goroutine in flight per MaxGoroutines slot, at most that many sources' reads
+// are ever resident in memory at once, instead of every .proc file's worth.
+type ReadSource struct {
+    SeqName string
+    Open    func() ([][]byte, error)
+}
+
+// ShardOpts configures RepeatGenome.ClassifyShards.
+type ShardOpts struct {
+    // MaxGoroutines bounds how many ReadSources are classified concurrently.
+    // <= 0 means unbounded (one goroutine per ReadSource).
+    MaxGoroutines int
+    // ChanBuffer sizes the shared response channel returned by
+    // ClassifyShards.
+    ChanBuffer int
+}
+
+// ClassifyShards classifies each ReadSource in inputs on its own goroutine,
+// bounded by opts.MaxGoroutines, and multiplexes every ReadResponse onto a
+// single shared channel that's closed once all shards finish. This avoids
+// concatenating every input into one flat slice up front, which is the
+// dominant memory cost for large genomes.
+func (rg *RepeatGenome) ClassifyShards(inputs []ReadSource, opts ShardOpts) <-chan ReadResponse {
+    out := make(chan ReadResponse, opts.ChanBuffer)
+
+    maxGoroutines := opts.MaxGoroutines
+    if maxGoroutines <= 0 || maxGoroutines > len(inputs) {
+        maxGoroutines = len(inputs)
+    }
+    sem := make(chan struct{}, maxGoroutines)
+
+    var wg sync.WaitGroup
+    wg.Add(len(inputs))
+    for _, input := range inputs {
+        input := input
+        go func() {
+            defer wg.Done()
+            sem <- struct{}{}
+            defer func() { <-sem }()
+
+            reads, err := input.Open()
+            if err != nil {
+                checkError(err)
+                return
+            }
+
+            var index uint64
+            for resp := range rg.GetReadClassChan(reads) {
+                resp.SeqName = input.SeqName
+                resp.Index = index
+                index++
+                out <- resp
+            }
+        }()
+    }
+
+    go func() {
+        wg.Wait()
+        close(out)
+    }()
+
+    return out
+}
@@ -1,7 +1,6 @@
 package repeatgenome
 
 import (
-    "bufio"
     "encoding/json"
     "fmt"
     "io"
@@ -18,12 +17,19 @@ type JSONNode struct {
 }
 
 func (repeatGenome *RepeatGenome) WriteClassJSON(useCumSize, printLeaves bool) {
-    tree := &repeatGenome.ClassTree
-
-    filename := repeatGenome.Name + ".classtree.json"
-    outfile, err := os.Create(filename)
+    writer, closeFile, err := CreateOutputFile(repeatGenome.Name+".classtree.json", repeatGenome.Flags.Compress)
     checkError(err)
-    defer outfile.Close()
+    defer closeFile()
+    checkError(repeatGenome.writeClassJSONTo(writer, useCumSize, printLeaves))
+}
+
+// writeClassJSONTo does the actual marshaling and writing for
+// WriteClassJSON, against an already-open writer. It exists so that
+// OutputFormat implementations (see format_legacy.go) can write through the
+// same file the caller opened via Filename()+CreateOutputFile, instead of
+// each reopening (and truncating) the path a second time.
+func (repeatGenome *RepeatGenome) writeClassJSONTo(w io.Writer, useCumSize, printLeaves bool) error {
+    tree := &repeatGenome.ClassTree
 
     classToCount := make(map[uint16]uint64)
     for i := range repeatGenome.Kmers {
@@ -43,8 +49,11 @@ func (repeatGenome *RepeatGenome) WriteClassJSON(useCumSize, printLeaves bool) {
     }
 
     jsonBytes, err := json.MarshalIndent(root, "", "\t")
-    checkError(err)
-    fmt.Fprint(outfile, string(jsonBytes))
+    if err != nil {
+        return err
+    }
+    _, err = fmt.Fprint(w, string(jsonBytes))
+    return err
 }
 
 func (classTree *ClassTree) jsonRecPopulate(jsonNode *JSONNode, classToCount map[uint16]uint64) {
@@ -100,24 +109,42 @@ func (refGenome *RepeatGenome) PrintChromInfo() {
     }
 }
 
-// a saner way of doing this would be to allocate a single k-long []byte and have a function populate it before printing
+// WriteMins writes minMap to the canonical <repeatGenome.Name>.mins file
+// (gzipped, with a ".gz" suffix, if repeatGenome.Flags.Compress is set).
+// Use WriteMinsToFile to write to a different path, e.g. for a filtered
+// variant that shouldn't clobber the canonical file.
 func (repeatGenome *RepeatGenome) WriteMins(minMap map[uint64]Kmers) error {
+    return repeatGenome.WriteMinsToFile(strings.Join([]string{repeatGenome.Name, ".mins"}, ""), minMap)
+}
+
+// WriteMinsToFile writes minMap to filename (gzipped per
+// repeatGenome.Flags.Compress, same as WriteMins).
+func (repeatGenome *RepeatGenome) WriteMinsToFile(filename string, minMap map[uint64]Kmers) error {
+    writer, closeFile, err := CreateOutputFile(filename, repeatGenome.Flags.Compress)
+    if err != nil {
+        return err
+    }
+    defer closeFile()
+    return repeatGenome.writeMinsTo(writer, minMap)
+}
+
+// a saner way of doing this would be to allocate a single k-long []byte and have a function populate it before printing
+//
+// writeMinsTo does the actual writing for WriteMins/WriteMinsToFile against
+// an already-open writer, so OutputFormat implementations (see
+// format_legacy.go) can write through the same file the caller opened via
+// Filename()+CreateOutputFile, instead of each reopening (and truncating)
+// the path a second time.
+func (repeatGenome *RepeatGenome) writeMinsTo(writer io.Writer, minMap map[uint64]Kmers) error {
     k := repeatGenome.K
     m := repeatGenome.M
     kmerBuf := make([]byte, k, k)
     minBuf := make([]byte, m, m)
-    filename := strings.Join([]string{repeatGenome.Name, ".mins"}, "")
-    outfile, err := os.Create(filename)
-    if err != nil {
-        return err
-    }
-    defer outfile.Close()
-    writer := bufio.NewWriter(outfile)
-    defer writer.Flush()
 
     var kmers Kmers
     var thisMin, kmerSeqInt uint64
     var lca_ID uint16
+    var err error
 
     for thisMin, kmers = range minMap {
         fillSeq(minBuf, thisMin)
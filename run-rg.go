@@ -1,14 +1,18 @@
 package main
 
 import (
+    "bufio"
     "bytes"
     "flag"
     "fmt"
+    "io"
     "io/ioutil"
     "jh/repeatgenome"
     "log"
     "os"
+    "path/filepath"
     "runtime/pprof"
+    "strings"
     "time"
 )
 
@@ -31,6 +35,116 @@ func fileLines(filepath string) (err error, linesBytes [][]byte) {
     }
 }
 
+// countFileLines counts filepath's lines the same way lines() does (every
+// line counts except a trailing run of empty ones), with a single streaming
+// pass so callers sizing a read count don't need to hold the whole file in
+// memory (see the readSources loop in main, which only opens each .proc file
+// for real once it's handed to ClassifyShards). Interior blank lines must
+// still count here: fileLines/lines() only trims trailing empty lines, so a
+// .proc file with a non-trailing blank line produces a (blank) read for it.
+func countFileLines(filepath string) (int, error) {
+    f, err := os.Open(filepath)
+    if err != nil {
+        return 0, err
+    }
+    defer f.Close()
+
+    count := 0
+    pendingBlanks := 0
+    scanner := bufio.NewScanner(f)
+    scanner.Buffer(make([]byte, 0, 64*1024), 1024*1024)
+    for scanner.Scan() {
+        if len(scanner.Bytes()) == 0 {
+            pendingBlanks++
+            continue
+        }
+        count += pendingBlanks + 1
+        pendingBlanks = 0
+    }
+    return count, scanner.Err()
+}
+
+// runFASTQMode classifies reads streamed from a FASTQ file (or a pair of
+// mate files), bypassing the .proc scan entirely. It's the -fastq/-fastq2
+// counterpart to the .proc-file loop in main().
+func runFASTQMode(rg *repeatgenome.RepeatGenome, fastqPath, fastq2Path string, minQual int, outputFormatName string, gzipOutput bool) {
+    f1, err := os.Open(fastqPath)
+    if err != nil {
+        log.Fatal(err)
+    }
+    defer f1.Close()
+
+    var format repeatgenome.OutputFormat
+    var formatWriter io.Writer
+    var closeFormat func() error
+    if outputFormatName != "" {
+        var ok bool
+        format, ok = repeatgenome.NewOutputFormat(outputFormatName)
+        if !ok {
+            log.Fatalf("unknown -output-format %q (known formats: %v)", outputFormatName, repeatgenome.OutputFormatNames())
+        }
+        formatWriter, closeFormat, err = repeatgenome.CreateOutputFile(format.Filename(rg), gzipOutput)
+        if err != nil {
+            log.Fatal(err)
+        }
+        defer closeFormat()
+        if err = format.Head(formatWriter, rg); err != nil {
+            log.Fatal(err)
+        }
+    }
+
+    var numReads, numClassified uint64
+    if fastq2Path != "" {
+        f2, err := os.Open(fastq2Path)
+        if err != nil {
+            log.Fatal(err)
+        }
+        defer f2.Close()
+
+        pairs, err := rg.ClassifyFASTQPair(f1, f2, minQual)
+        if err != nil {
+            log.Fatal(err)
+        }
+        for pair := range pairs {
+            if format != nil {
+                resp := repeatgenome.ReadResponse{Read: pair.Read1.Seq, ClassNode: pair.ClassNode, Index: numReads}
+                if err = format.Print(formatWriter, resp); err != nil {
+                    log.Fatal(err)
+                }
+            }
+            numReads++
+            if pair.ClassNode != nil {
+                numClassified++
+            }
+        }
+    } else {
+        responses, err := rg.ClassifyFASTQ(f1, minQual)
+        if err != nil {
+            log.Fatal(err)
+        }
+        for response := range responses {
+            if format != nil {
+                if err = format.Print(formatWriter, response); err != nil {
+                    log.Fatal(err)
+                }
+            }
+            numReads++
+            if response.ClassNode != nil {
+                numClassified++
+            }
+        }
+    }
+
+    if format != nil {
+        if err = format.Finish(formatWriter); err != nil {
+            log.Fatal(err)
+        }
+    }
+
+    fmt.Printf("%.2f%% of reads were classified with a repeat sequence (%d of %d)\n", 100*(float64(numClassified)/float64(numReads)), numClassified, numReads)
+    fmt.Println()
+}
+
 func main() {
 
     if len(os.Args) < 2 {
@@ -48,6 +162,17 @@ func main() {
     verifyClass := flag.Bool("verify_class", false, "run classification a second time, with SAM-formatted reads, to find percent correct classification")
     k_arg := flag.Uint("k", 31, "kmer length")
     m_arg := flag.Uint("m", 15, "minimizer length")
+    outputFormatName := flag.String("output-format", "", "write read classifications using the named OutputFormat (e.g. kraken, json-lines); unset disables classification output")
+    maxGoroutines := flag.Int("max-goroutines", 0, "cap concurrent per-file classification goroutines (0 = unbounded)")
+    perChromOutput := flag.Bool("per-chrom-output", false, "with -output-format, write one output file per input file instead of one combined file")
+    gzipOutput := flag.Bool("gzip", false, "gzip (in parallel, via pgzip) every file repeatgenome writes")
+    npyMode := flag.String("npy", "", "write the kmer classification matrix to <genomeName>.kmers.npy for numpy.load, using the given mode (\"lca-id\" or \"one-hot\")")
+    caseReadsPath := flag.String("case-reads", "", "path to a .proc file of case reads; with -ctrl-reads, filters the .mins file by chi-square p-value before it's written")
+    ctrlReadsPath := flag.String("ctrl-reads", "", "path to a .proc file of control reads; see -case-reads")
+    pValue := flag.Float64("p-value", 0.05, "maximum chi-square p-value for a minimizer to survive -case-reads/-ctrl-reads filtering")
+    fastqPath := flag.String("fastq", "", "classify reads streamed from this FASTQ file (optionally gzipped) instead of <genomeName>-reads/*.proc")
+    fastq2Path := flag.String("fastq2", "", "with -fastq, the second mate file of a paired-end FASTQ run")
+    minQual := flag.Int("min-qual", 0, "mask FASTQ bases with phred quality below this cutoff as 'N' before classifying (0 disables masking)")
     flag.Parse()
 
     if *cpuProfile {
@@ -103,7 +228,7 @@ func main() {
         fmt.Println()
     }
 
-    rgFlags := repeatgenome.Flags{*debug, *cpuProfile, *memProfile, genKraken, writeKraken, *writeJSON}
+    rgFlags := repeatgenome.Flags{*debug, *cpuProfile, *memProfile, genKraken, writeKraken, *writeJSON, *gzipOutput}
     err, rg := repeatgenome.Generate(genomeName, k, m, rgFlags)
     if err != nil {
         fmt.Println("./run-rg: RepeatGenome generation failed")
@@ -122,6 +247,40 @@ func main() {
     fmt.Println(classNodesWithRepeats, "class nodes with repeats")
     fmt.Println()
 
+    if *npyMode != "" {
+        npyPath := genomeName + ".kmers.npy"
+        if err := rg.WriteKmerMatrixNPY(npyPath, *npyMode); err != nil {
+            log.Fatal(err)
+        }
+        fmt.Println("wrote kmer classification matrix to", npyPath)
+        fmt.Println()
+    }
+
+    if *caseReadsPath != "" && *ctrlReadsPath != "" {
+        _, caseReads := fileLines(*caseReadsPath)
+        _, ctrlReads := fileLines(*ctrlReadsPath)
+        if caseReads == nil || ctrlReads == nil {
+            log.Fatal("failed to read -case-reads/-ctrl-reads")
+        }
+        filteredMins, err := rg.FilterMinsByCaseControl(caseReads, ctrlReads, *pValue)
+        if err != nil {
+            log.Fatal(err)
+        }
+        fmt.Printf("chi-square filtering kept %d of %d minimizers (p <= %.4g)\n", len(filteredMins), len(rg.MinMap), *pValue)
+        filteredMinsPath := genomeName + ".filtered.mins"
+        if err := rg.WriteMinsToFile(filteredMinsPath, filteredMins); err != nil {
+            log.Fatal(err)
+        }
+        fmt.Println("wrote filtered minimizers to", filteredMinsPath)
+        fmt.Println()
+    }
+
+    if *fastqPath != "" {
+        runFASTQMode(rg, *fastqPath, *fastq2Path, *minQual, *outputFormatName, *gzipOutput)
+        fmt.Println(rg.Name, "successfully generated - exiting")
+        return
+    }
+
     workingDirName, err := os.Getwd()
     if err != nil {
         log.Fatal(err)
@@ -141,32 +300,127 @@ func main() {
             processedFiles = append(processedFiles, fileinfo)
         }
     }
-    readsBytes := [][]byte{}
+    readSources := []repeatgenome.ReadSource{}
+    numInputReads := uint64(0)
     for _, fileinfo := range processedFiles {
-        _, theseReadsBytes := fileLines(readsDirName + "/" + fileinfo.Name())
-        for _, bytesLine := range theseReadsBytes {
-            readsBytes = append(readsBytes, bytesLine)
+        path := readsDirName + "/" + fileinfo.Name()
+        seqName := strings.TrimSuffix(fileinfo.Name(), ".proc")
+
+        numLines, err := countFileLines(path)
+        if err != nil {
+            log.Fatal(err)
+        }
+        numInputReads += uint64(numLines)
+
+        readSources = append(readSources, repeatgenome.ReadSource{
+            SeqName: seqName,
+            Open: func() ([][]byte, error) {
+                err, theseReadsBytes := fileLines(path)
+                return theseReadsBytes, err
+            },
+        })
+    }
+
+    var format repeatgenome.OutputFormat
+    var formatFile io.Writer
+    var closeFormatFile func() error
+    if *outputFormatName != "" && !*perChromOutput {
+        var ok bool
+        format, ok = repeatgenome.NewOutputFormat(*outputFormatName)
+        if !ok {
+            log.Fatalf("unknown -output-format %q (known formats: %v)", *outputFormatName, repeatgenome.OutputFormatNames())
+        }
+        formatFile, closeFormatFile, err = repeatgenome.CreateOutputFile(format.Filename(rg), *gzipOutput)
+        if err != nil {
+            log.Fatal(err)
+        }
+        defer closeFormatFile()
+        if err = format.Head(formatFile, rg); err != nil {
+            log.Fatal(err)
         }
     }
 
+    perChromFiles := make(map[string]io.Writer)
+    perChromCloseFuncs := make(map[string]func() error)
+    perChromFormats := make(map[string]repeatgenome.OutputFormat)
+    if *outputFormatName != "" && *perChromOutput {
+        defer func() {
+            for _, closeFn := range perChromCloseFuncs {
+                closeFn()
+            }
+        }()
+    }
+
+    chanBuffer := *maxGoroutines
+    if chanBuffer < 0 {
+        chanBuffer = 0
+    }
+    shardOpts := repeatgenome.ShardOpts{MaxGoroutines: *maxGoroutines, ChanBuffer: chanBuffer}
     startTime := time.Now()
     var numReads, numClassifiedReads uint64 = 0, 0
-    for response := range rg.GetReadClassChan(readsBytes) {
-        _, classNode := response.Read, response.ClassNode
+    for response := range rg.ClassifyShards(readSources, shardOpts) {
+        classNode := response.ClassNode
+        if *outputFormatName != "" {
+            if *perChromOutput {
+                chromFile, ok := perChromFiles[response.SeqName]
+                chromFormat := perChromFormats[response.SeqName]
+                if !ok {
+                    var formatOK bool
+                    chromFormat, formatOK = repeatgenome.NewOutputFormat(*outputFormatName)
+                    if !formatOK {
+                        log.Fatalf("unknown -output-format %q (known formats: %v)", *outputFormatName, repeatgenome.OutputFormatNames())
+                    }
+                    chromFilename := rg.Name + "." + response.SeqName + filepath.Ext(chromFormat.Filename(rg))
+                    var closeChromFile func() error
+                    chromFile, closeChromFile, err = repeatgenome.CreateOutputFile(chromFilename, *gzipOutput)
+                    if err != nil {
+                        log.Fatal(err)
+                    }
+                    if err = chromFormat.Head(chromFile, rg); err != nil {
+                        log.Fatal(err)
+                    }
+                    perChromFiles[response.SeqName] = chromFile
+                    perChromCloseFuncs[response.SeqName] = closeChromFile
+                    perChromFormats[response.SeqName] = chromFormat
+                }
+                if err = chromFormat.Print(chromFile, response); err != nil {
+                    log.Fatal(err)
+                }
+            } else if err = format.Print(formatFile, response); err != nil {
+                log.Fatal(err)
+            }
+        }
         numReads++
         if classNode != nil {
             numClassifiedReads++
         }
     }
+    if *outputFormatName != "" {
+        if *perChromOutput {
+            for seqName, chromFile := range perChromFiles {
+                chromFormat := perChromFormats[seqName]
+                if cf, ok := chromFormat.(repeatgenome.ChromFinisher); ok {
+                    err = cf.FinishChrom(workingDirName, seqName, chromFile)
+                } else {
+                    err = chromFormat.Finish(chromFile)
+                }
+                if err != nil {
+                    log.Fatal(err)
+                }
+            }
+        } else if err = format.Finish(formatFile); err != nil {
+            log.Fatal(err)
+        }
+    }
     netTime := time.Since(startTime)
 
-    if numReads != uint64(len(readsBytes)) {
-        panic("not all reads, or too many reads, returned from RepeatGenome.GetReadClassChan()")
+    if numReads != numInputReads {
+        panic("not all reads, or too many reads, returned from RepeatGenome.ClassifyShards()")
     }
 
     if rg.Flags.Debug {
         classCount := make(map[*repeatgenome.ClassNode]uint64)
-        for response := range rg.GetReadClassChan(readsBytes) {
+        for response := range rg.ClassifyShards(readSources, shardOpts) {
             if response.ClassNode != nil {
                 classCount[response.ClassNode]++
             }